@@ -0,0 +1,41 @@
+package ar
+
+// Callback is invoked for a lifecycle event with the triggering model.
+type Callback func(model interface{}) error
+
+var callbacks = map[string][]Callback{}
+
+// RegisterCallback adds fn to the chain run for name: before_validation,
+// after_validation, before_save, before_create, before_update,
+// after_create, after_update, after_save, before_destroy, after_destroy,
+// after_find.
+func RegisterCallback(name string, fn Callback) {
+	callbacks[name] = append(callbacks[name], fn)
+}
+
+// RunCallbacks runs every callback registered for name with model, in
+// order, stopping at the first error.
+func RunCallbacks(name string, model interface{}) error {
+	for _, fn := range callbacks[name] {
+		if err := fn(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hook interfaces a model may implement as an alternative to
+// RegisterCallback.
+type (
+	BeforeValidationHook interface{ BeforeValidation() error }
+	AfterValidationHook  interface{ AfterValidation() error }
+	BeforeSaveHook       interface{ BeforeSave() error }
+	BeforeCreateHook     interface{ BeforeCreate() error }
+	BeforeUpdateHook     interface{ BeforeUpdate() error }
+	AfterCreateHook      interface{ AfterCreate(tx *Tx) error }
+	AfterUpdateHook      interface{ AfterUpdate(tx *Tx) error }
+	AfterSaveHook        interface{ AfterSave(tx *Tx) error }
+	BeforeDestroyHook    interface{ BeforeDestroy() error }
+	AfterDestroyHook     interface{ AfterDestroy() error }
+	AfterFindHook        interface{ AfterFind() error }
+)
@@ -0,0 +1,114 @@
+package ar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect adapts SQL generation to a specific database.
+type Dialect interface {
+	Name() string
+	Quote(ident string) string
+	Placeholder(n int) string
+	InsertReturning(table, pk string) string
+	LimitOffset(limit, offset int) string
+}
+
+var dialects = map[string]Dialect{}
+
+// RegisterDialect makes d available by name for UseDialect.
+func RegisterDialect(name string, d Dialect) {
+	dialects[name] = d
+}
+
+var current Dialect = mysqlDialect{}
+
+// UseDialect selects the dialect consulted by query building and Rebind.
+func UseDialect(name string) error {
+	d, ok := dialects[name]
+	if !ok {
+		return fmt.Errorf("ar: unknown dialect %q", name)
+	}
+	current = d
+	return nil
+}
+
+// CurrentDialect returns the dialect selected by the most recent
+// UseDialect call. It defaults to mysql.
+func CurrentDialect() Dialect {
+	return current
+}
+
+// Rebind rewrites a query's "?" placeholders into the current
+// dialect's own syntax; a no-op for dialects whose placeholder is "?".
+func Rebind(query string) string {
+	if current.Placeholder(1) == "?" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString(current.Placeholder(n))
+	}
+	return b.String()
+}
+
+func init() {
+	RegisterDialect("mysql", mysqlDialect{})
+	RegisterDialect("sqlite3", sqliteDialect{})
+	RegisterDialect("postgres", postgresDialect{})
+	RegisterDialect("mssql", mssqlDialect{})
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string              { return "mysql" }
+func (mysqlDialect) Quote(ident string) string { return "`" + ident + "`" }
+func (mysqlDialect) Placeholder(n int) string  { return "?" }
+func (mysqlDialect) InsertReturning(table, pk string) string {
+	return ""
+}
+func (mysqlDialect) LimitOffset(limit, offset int) string {
+	return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string              { return "sqlite3" }
+func (sqliteDialect) Quote(ident string) string { return `"` + ident + `"` }
+func (sqliteDialect) Placeholder(n int) string  { return "?" }
+func (sqliteDialect) InsertReturning(table, pk string) string {
+	return ""
+}
+func (sqliteDialect) LimitOffset(limit, offset int) string {
+	return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string              { return "postgres" }
+func (postgresDialect) Quote(ident string) string { return `"` + ident + `"` }
+func (postgresDialect) Placeholder(n int) string  { return fmt.Sprintf("$%d", n) }
+func (d postgresDialect) InsertReturning(table, pk string) string {
+	return fmt.Sprintf("RETURNING %s", d.Quote(pk))
+}
+func (postgresDialect) LimitOffset(limit, offset int) string {
+	return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+}
+
+type mssqlDialect struct{}
+
+func (mssqlDialect) Name() string              { return "mssql" }
+func (mssqlDialect) Quote(ident string) string { return "[" + ident + "]" }
+func (mssqlDialect) Placeholder(n int) string  { return fmt.Sprintf("@p%d", n) }
+func (d mssqlDialect) InsertReturning(table, pk string) string {
+	return fmt.Sprintf("OUTPUT INSERTED.%s", d.Quote(pk))
+}
+func (mssqlDialect) LimitOffset(limit, offset int) string {
+	return fmt.Sprintf("OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", offset, limit)
+}
@@ -0,0 +1,297 @@
+package ar
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// column describes one field of a model, parsed from its `ar` struct tag.
+type column struct {
+	name   string
+	typ    reflect.Type
+	pk     bool
+	null   bool
+	size   int
+	deflt  string
+	index  bool
+	unique bool
+}
+
+// AutoMigrate brings db's schema for each model in models in line with
+// its struct tags: creating its table if missing, adding any column the
+// struct has and the table doesn't, and creating any index/unique column.
+func AutoMigrate(db *sql.DB, models ...interface{}) error {
+	_, err := autoMigrate(db, false, models...)
+	return err
+}
+
+// DryRunAutoMigrate returns the statements AutoMigrate would run,
+// without executing them.
+func DryRunAutoMigrate(db *sql.DB, models ...interface{}) ([]string, error) {
+	return autoMigrate(db, true, models...)
+}
+
+func autoMigrate(db *sql.DB, dryRun bool, models ...interface{}) ([]string, error) {
+	var statements []string
+	run := func(stmt string) error {
+		statements = append(statements, stmt)
+		if dryRun {
+			return nil
+		}
+		_, err := db.Exec(stmt)
+		return err
+	}
+
+	for _, model := range models {
+		table, columns := describeModel(model)
+
+		existing, err := existingColumns(db, table)
+		if err != nil {
+			return statements, err
+		}
+
+		if len(existing) == 0 {
+			if err := run(createTableStatement(table, columns)); err != nil {
+				return statements, err
+			}
+		} else {
+			for _, c := range columns {
+				if existing[c.name] {
+					continue
+				}
+				if err := run(addColumnStatement(table, c)); err != nil {
+					return statements, err
+				}
+			}
+		}
+
+		for _, c := range columns {
+			if !c.index && !c.unique {
+				continue
+			}
+			if err := run(createIndexStatement(table, c)); err != nil {
+				return statements, err
+			}
+		}
+	}
+	return statements, nil
+}
+
+// existingColumns reports table's column names, or none for an unknown
+// table, letting AutoMigrate tell a new table from one needing columns.
+func existingColumns(db *sql.DB, table string) (map[string]bool, error) {
+	cols := map[string]bool{}
+
+	if current.Name() == "sqlite3" {
+		rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var cid, notnull, pk int
+			var name, ctype string
+			var dflt sql.NullString
+			if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+				return nil, err
+			}
+			cols[name] = true
+		}
+		return cols, nil
+	}
+
+	rows, err := db.Query(Rebind("SELECT column_name FROM information_schema.columns WHERE table_name = ?"), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols[name] = true
+	}
+	return cols, nil
+}
+
+func describeModel(model interface{}) (string, []column) {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	columns := []column{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if tag, ok := f.Tag.Lookup("ar"); ok && tag == "-" {
+			continue
+		}
+		columns = append(columns, parseColumn(f))
+	}
+	return toSnakeCase(t.Name()) + "s", columns
+}
+
+func parseColumn(f reflect.StructField) column {
+	c := column{name: toSnakeCase(f.Name), typ: f.Type, null: true}
+	for _, part := range strings.Split(f.Tag.Get("ar"), ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, ":", 2)
+		switch kv[0] {
+		case "pk":
+			c.pk = true
+			c.null = false
+		case "null":
+			c.null = true
+		case "index":
+			c.index = true
+		case "unique":
+			c.unique = true
+			c.null = false
+		case "size":
+			if len(kv) == 2 {
+				c.size, _ = strconv.Atoi(kv[1])
+			}
+		case "default":
+			if len(kv) == 2 {
+				c.deflt = kv[1]
+			}
+		}
+	}
+	return c
+}
+
+func toSnakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToLower(b.String())
+}
+
+func sqlType(c column) string {
+	switch current.Name() {
+	case "postgres":
+		switch {
+		case isIntKind(c.typ) && c.pk:
+			return "SERIAL"
+		case isIntKind(c.typ):
+			return "INTEGER"
+		case c.typ.Kind() == reflect.String && c.size > 0:
+			return fmt.Sprintf("VARCHAR(%d)", c.size)
+		case c.typ.Kind() == reflect.String:
+			return "TEXT"
+		case c.typ.Kind() == reflect.Bool:
+			return "BOOLEAN"
+		}
+	case "mssql":
+		switch {
+		case isIntKind(c.typ) && c.pk:
+			return "INT IDENTITY(1,1)"
+		case isIntKind(c.typ):
+			return "INT"
+		case c.typ.Kind() == reflect.String && c.size > 0:
+			return fmt.Sprintf("NVARCHAR(%d)", c.size)
+		case c.typ.Kind() == reflect.String:
+			return "NVARCHAR(MAX)"
+		case c.typ.Kind() == reflect.Bool:
+			return "BIT"
+		}
+	case "sqlite3":
+		switch {
+		case isIntKind(c.typ) && c.pk:
+			return "INTEGER PRIMARY KEY AUTOINCREMENT"
+		case isIntKind(c.typ):
+			return "INTEGER"
+		case c.typ.Kind() == reflect.String:
+			return "TEXT"
+		case c.typ.Kind() == reflect.Bool:
+			return "BOOLEAN"
+		}
+	default: // mysql
+		switch {
+		case isIntKind(c.typ) && c.pk:
+			return "INTEGER PRIMARY KEY AUTO_INCREMENT"
+		case isIntKind(c.typ):
+			return "INTEGER"
+		case c.typ.Kind() == reflect.String:
+			size := c.size
+			if size == 0 {
+				size = 255
+			}
+			return fmt.Sprintf("VARCHAR(%d)", size)
+		case c.typ.Kind() == reflect.Bool:
+			return "TINYINT(1)"
+		}
+	}
+	return "TEXT"
+}
+
+func isIntKind(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func columnDDL(c column) string {
+	ddl := current.Quote(c.name) + " " + sqlType(c)
+	if !c.pk {
+		if !c.null {
+			ddl += " NOT NULL"
+		}
+		if c.deflt != "" {
+			ddl += " DEFAULT " + c.deflt
+		}
+	}
+	if c.unique {
+		ddl += " UNIQUE"
+	}
+	return ddl
+}
+
+func createTableStatement(table string, columns []column) string {
+	defs := make([]string, len(columns))
+	for i, c := range columns {
+		defs[i] = columnDDL(c)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", current.Quote(table), strings.Join(defs, ", "))
+}
+
+// addColumnStatement returns an ALTER TABLE adding c to table. T-SQL's
+// ADD doesn't take the COLUMN keyword at all, unlike the other dialects.
+func addColumnStatement(table string, c column) string {
+	if current.Name() == "mssql" {
+		return fmt.Sprintf("ALTER TABLE %s ADD %s", current.Quote(table), columnDDL(c))
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", current.Quote(table), columnDDL(c))
+}
+
+// createIndexStatement returns a CREATE INDEX for c. IF NOT EXISTS is
+// only valid there on postgres and sqlite3, so mysql and mssql omit it.
+func createIndexStatement(table string, c column) string {
+	kind := "INDEX"
+	if c.unique {
+		kind = "UNIQUE INDEX"
+	}
+	name := fmt.Sprintf("idx_%s_%s", table, c.name)
+	switch current.Name() {
+	case "postgres", "sqlite3":
+		return fmt.Sprintf("CREATE %s IF NOT EXISTS %s ON %s (%s)", kind, name, current.Quote(table), current.Quote(c.name))
+	default: // mysql, mssql
+		return fmt.Sprintf("CREATE %s %s ON %s (%s)", kind, name, current.Quote(table), current.Quote(c.name))
+	}
+}
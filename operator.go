@@ -0,0 +1,80 @@
+package ar
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ParseCondition expands a column argument's "__op" suffix (gt, gte,
+// lt, lte, not, like, in, between, isnull) into a SQL fragment and its
+// placeholders. cond is returned unchanged if it has no such suffix.
+func ParseCondition(cond string, args ...interface{}) (string, []interface{}) {
+	column, op, ok := splitOperatorSuffix(cond)
+	if !ok {
+		return cond, args
+	}
+
+	switch op {
+	case "gt":
+		return column + " > ?", args
+	case "gte":
+		return column + " >= ?", args
+	case "lt":
+		return column + " < ?", args
+	case "lte":
+		return column + " <= ?", args
+	case "not":
+		return column + " != ?", args
+	case "like":
+		return column + " LIKE ?", args
+	case "in":
+		if len(args) == 0 {
+			return "1=0", nil
+		}
+		values := toInterfaceSlice(args[0])
+		if len(values) == 0 {
+			return "1=0", nil
+		}
+		return column + " IN (" + questionMarks(len(values)) + ")", values
+	case "between":
+		return column + " BETWEEN ? AND ?", args
+	case "isnull":
+		if len(args) == 0 {
+			return column + " IS NULL", nil
+		}
+		if negate, ok := args[0].(bool); ok && !negate {
+			return column + " IS NOT NULL", nil
+		}
+		return column + " IS NULL", nil
+	default:
+		return cond, args
+	}
+}
+
+func splitOperatorSuffix(cond string) (column, op string, ok bool) {
+	i := strings.LastIndex(cond, "__")
+	if i < 0 {
+		return cond, "", false
+	}
+	return cond[:i], cond[i+2:], true
+}
+
+func toInterfaceSlice(v interface{}) []interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return []interface{}{v}
+	}
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+func questionMarks(n int) string {
+	marks := make([]string, n)
+	for i := range marks {
+		marks[i] = "?"
+	}
+	return strings.Join(marks, ",")
+}
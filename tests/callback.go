@@ -0,0 +1,74 @@
+package tests
+
+import "github.com/monochromegane/argen"
+
+// dispatch runs the registered callback chain for name, then model's
+// own hook method if it implements the matching interface.
+func dispatch(name string, model interface{}) error {
+	if err := ar.RunCallbacks(name, model); err != nil {
+		return err
+	}
+	switch name {
+	case "before_validation":
+		if h, ok := model.(ar.BeforeValidationHook); ok {
+			return h.BeforeValidation()
+		}
+	case "after_validation":
+		if h, ok := model.(ar.AfterValidationHook); ok {
+			return h.AfterValidation()
+		}
+	case "before_save":
+		if h, ok := model.(ar.BeforeSaveHook); ok {
+			return h.BeforeSave()
+		}
+	case "before_create":
+		if h, ok := model.(ar.BeforeCreateHook); ok {
+			return h.BeforeCreate()
+		}
+	case "before_update":
+		if h, ok := model.(ar.BeforeUpdateHook); ok {
+			return h.BeforeUpdate()
+		}
+	case "before_destroy":
+		if h, ok := model.(ar.BeforeDestroyHook); ok {
+			return h.BeforeDestroy()
+		}
+	case "after_destroy":
+		if h, ok := model.(ar.AfterDestroyHook); ok {
+			return h.AfterDestroy()
+		}
+	case "after_find":
+		if h, ok := model.(ar.AfterFindHook); ok {
+			return h.AfterFind()
+		}
+	}
+	return nil
+}
+
+// dispatchTx is dispatch for the tx-carrying after_create/after_update/
+// after_save hooks. The callback chain always runs, but a model's own
+// hook method is skipped when tx is nil rather than handed a tx it
+// would likely dereference; use SaveTx to have those hooks fire.
+func dispatchTx(name string, model interface{}, tx *ar.Tx) error {
+	if err := ar.RunCallbacks(name, model); err != nil {
+		return err
+	}
+	if tx == nil {
+		return nil
+	}
+	switch name {
+	case "after_create":
+		if h, ok := model.(ar.AfterCreateHook); ok {
+			return h.AfterCreate(tx)
+		}
+	case "after_update":
+		if h, ok := model.(ar.AfterUpdateHook); ok {
+			return h.AfterUpdate(tx)
+		}
+	case "after_save":
+		if h, ok := model.(ar.AfterSaveHook); ok {
+			return h.AfterSave(tx)
+		}
+	}
+	return nil
+}
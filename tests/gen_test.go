@@ -2,6 +2,7 @@ package tests
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"reflect"
@@ -13,13 +14,13 @@ import (
 )
 
 func TestMain(m *testing.M) {
-	db, err := testDb()
+	db, dialect, err := testDb()
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 
-	Use(db)
+	Use(db, dialect)
 	LogMode(true)
 	for _, q := range testTables() {
 		_, err = db.Exec(q)
@@ -419,6 +420,151 @@ func TestAll(t *testing.T) {
 	}
 }
 
+func TestTransaction(t *testing.T) {
+	defer User{}.DeleteAll()
+
+	err := Transaction(func(tx *ar.Tx) error {
+		u := &User{Name: "committed"}
+		if _, errs := u.SaveTx(tx); errs != nil {
+			return errs
+		}
+
+		tx.Transaction(func(tx *ar.Tx) error {
+			u := &User{Name: "rolled-back"}
+			if _, errs := u.SaveTx(tx); errs != nil {
+				return errs
+			}
+			return fmt.Errorf("force inner rollback")
+		})
+		return nil
+	})
+	assertError(t, err)
+
+	users, _ := User{}.Query()
+	if len(users) != 1 || users[0].Name != "committed" {
+		t.Errorf("expected only the committed row to survive, but %v", users)
+	}
+}
+
+func TestDryRunAutoMigrate(t *testing.T) {
+	indexExists := func() bool {
+		var name string
+		return db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'index' AND name = 'idx_posts_user_id'").Scan(&name) == nil
+	}
+
+	stmts, err := Post{}.DryRunAutoMigrate()
+	assertError(t, err)
+	if len(stmts) == 0 {
+		t.Errorf("expected at least one statement, but got none")
+	}
+	if indexExists() {
+		t.Errorf("DryRunAutoMigrate should not have created the index")
+	}
+
+	if err := (Post{}).AutoMigrate(); err != nil {
+		t.Errorf("error should be nil, but %v", err)
+	}
+	if !indexExists() {
+		t.Errorf("AutoMigrate should have created the index")
+	}
+}
+
+func TestCallbacks(t *testing.T) {
+	defer User{}.DeleteAll()
+
+	var fired []string
+	ar.RegisterCallback("before_save", func(model interface{}) error {
+		fired = append(fired, "before_save")
+		return nil
+	})
+	ar.RegisterCallback("after_save", func(model interface{}) error {
+		fired = append(fired, "after_save")
+		return nil
+	})
+	ar.RegisterCallback("before_destroy", func(model interface{}) error {
+		fired = append(fired, "before_destroy")
+		return nil
+	})
+	ar.RegisterCallback("after_destroy", func(model interface{}) error {
+		fired = append(fired, "after_destroy")
+		return nil
+	})
+
+	u := &User{Name: "test"}
+	u.Save()
+	User{}.DeleteAll()
+
+	expect := []string{"before_save", "after_save", "before_destroy", "after_destroy"}
+	if len(fired) != len(expect) {
+		t.Errorf("callbacks fired should be %v, but %v", expect, fired)
+		return
+	}
+	for i, name := range expect {
+		if fired[i] != name {
+			t.Errorf("callbacks fired should be %v, but %v", expect, fired)
+			break
+		}
+	}
+}
+
+func TestPreload(t *testing.T) {
+	defer func() {
+		User{}.DeleteAll()
+		Post{}.DeleteAll()
+	}()
+
+	u, _ := User{}.Create(UserParams{Name: "test1"})
+	expect, _ := Post{}.Create(PostParams{UserId: u.Id, Name: "name"})
+
+	users, err := User{}.Where("id", u.Id).Preload("Posts").Query()
+	assertError(t, err)
+	if len(users) != 1 {
+		t.Errorf("record count should be 1, but %v", len(users))
+	}
+
+	// Preload caches into the unexported posts field directly, so this
+	// check must not call Posts() itself or it would hide a broken
+	// Preload behind the very query it's meant to avoid.
+	if len(users[0].posts) != 1 || users[0].posts[0].Id != expect.Id {
+		t.Errorf("preloaded posts should be %v, but %v", []*Post{expect}, users[0].posts)
+	}
+}
+
+func TestWhereOperators(t *testing.T) {
+	defer User{}.DeleteAll()
+
+	for _, name := range []string{"aaa", "bbb", "ccc"} {
+		u := &User{Name: name}
+		u.Save()
+	}
+
+	users, err := User{}.WhereNameLike("b%").Query()
+	assertError(t, err)
+	if len(users) != 1 || users[0].Name != "bbb" {
+		t.Errorf("expected one match bbb, but %v", users)
+	}
+
+	users, err = User{}.WhereIdIn([]int{}).Query()
+	assertError(t, err)
+	if len(users) != 0 {
+		t.Errorf("empty WhereIdIn should match nothing, but %v", users)
+	}
+}
+
+func TestDialectRebind(t *testing.T) {
+	defer ar.UseDialect(ar.CurrentDialect().Name())
+
+	ar.UseDialect("postgres")
+	if got := ar.Rebind("SELECT 1 WHERE a = ? AND b = ?"); got != "SELECT 1 WHERE a = $1 AND b = $2" {
+		t.Errorf("rebound query should be %q, but %q", "SELECT 1 WHERE a = $1 AND b = $2", got)
+	}
+
+	ar.UseDialect("sqlite3")
+	if got := ar.Rebind("SELECT 1 WHERE a = ?"); got != "SELECT 1 WHERE a = ?" {
+		t.Errorf("rebound query should be left alone for sqlite3, but got %q", got)
+	}
+}
+
 func assertEqualStruct(t *testing.T, expect, actual interface{}) {
 	if !reflect.DeepEqual(expect, actual) {
 		t.Errorf("struct should be equal to %v, but %v", expect, actual)
@@ -431,14 +577,16 @@ func assertError(t *testing.T, err error) {
 	}
 }
 
-func testDb() (*sql.DB, error) {
+func testDb() (*sql.DB, string, error) {
 	switch os.Getenv("DB") {
 	case "mysql":
-		return sql.Open("mysql", "travis@/argen_test")
+		db, err := sql.Open("mysql", "travis@/argen_test")
+		return db, "mysql", err
 	case "sqlite3", "":
-		return sql.Open("sqlite3", ":memory:")
+		db, err := sql.Open("sqlite3", ":memory:")
+		return db, "sqlite3", err
 	}
-	return nil, nil
+	return nil, "", nil
 }
 
 func testTables() []string {
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/monochromegane/argen"
+)
+
+// execer is satisfied by both *sql.DB and *ar.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// execInsert runs query against e and scans the new row's pk into id,
+// using RETURNING/OUTPUT when the dialect has one, else LastInsertId.
+func execInsert(e execer, query string, args []interface{}, table, pk string, id *int) error {
+	if returning := ar.CurrentDialect().InsertReturning(table, pk); returning != "" {
+		return e.QueryRow(ar.Rebind(insertWithReturning(query, returning)), args...).Scan(id)
+	}
+
+	result, err := e.Exec(ar.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	lastId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	*id = int(lastId)
+	return nil
+}
+
+// insertWithReturning splices returning into query. mssql's OUTPUT
+// clause must sit between the column list and VALUES, not trail the
+// whole statement like postgres's RETURNING does.
+func insertWithReturning(query, returning string) string {
+	if ar.CurrentDialect().Name() != "mssql" {
+		return query + " " + returning
+	}
+	if i := strings.Index(strings.ToUpper(query), " VALUES"); i >= 0 {
+		return query[:i] + " " + returning + query[i:]
+	}
+	return query + " " + returning
+}
@@ -0,0 +1,447 @@
+package tests
+
+import (
+	"fmt"
+
+	"github.com/monochromegane/argen"
+)
+
+type Post struct {
+	Id     int    `ar:"pk"`
+	UserId int    `ar:"index"`
+	Name   string `ar:"size:255"`
+
+	user *User
+}
+
+func (m *Post) fieldByName(name string) interface{} {
+	switch name {
+	case "id":
+		return &m.Id
+	case "user_id":
+		return &m.UserId
+	case "name":
+		return &m.Name
+	default:
+		return ""
+	}
+}
+
+func (m *Post) fieldsByName(names []string) []interface{} {
+	fields := []interface{}{}
+	for _, n := range names {
+		f := m.fieldByName(n)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func (m Post) Select(columns ...string) *PostRelation {
+	r := m.newRelation()
+	r.Relation.Columns(columns...)
+	return r
+}
+
+func (m Post) Find(id int) (*Post, error) {
+	return m.newRelation().Find(id)
+}
+
+func (r *PostRelation) Find(id int) (*Post, error) {
+	return r.Where("id", id).QueryRow()
+}
+
+type PostParams Post
+
+func (m Post) Create(p PostParams) (*Post, *ar.Errors) {
+	n := &Post{
+		Id:     p.Id,
+		UserId: p.UserId,
+		Name:   p.Name,
+	}
+	_, errs := n.Save()
+	return n, errs
+}
+
+func (m *Post) IsNewRecord() bool {
+	return ar.IsZero(m.Id)
+}
+
+func (m *Post) IsPersistent() bool {
+	return !m.IsNewRecord()
+}
+
+func (m *Post) Save() (bool, *ar.Errors) {
+	return m.save(nil)
+}
+
+func (m *Post) SaveTx(tx *ar.Tx) (bool, *ar.Errors) {
+	return m.save(tx)
+}
+
+func (m *Post) save(tx *ar.Tx) (bool, *ar.Errors) {
+	errs := &ar.Errors{}
+
+	if err := dispatch("before_validation", m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+	ok, validationErrs := m.IsValid()
+	if err := dispatch("after_validation", m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+	if !ok {
+		return false, validationErrs
+	}
+
+	if err := dispatch("before_save", m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
+	isCreate := m.IsNewRecord()
+	if isCreate {
+		if err := dispatch("before_create", m); err != nil {
+			errs.Add("base", err)
+			return false, errs
+		}
+	} else if err := dispatch("before_update", m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
+	if isCreate {
+		ins := ar.NewInsert()
+		q, b := ins.Table("posts").Params(map[string]interface{}{
+			"user_id": m.UserId,
+			"name":    m.Name,
+		}).Build()
+
+		e := execer(db)
+		if tx != nil {
+			e = tx
+		}
+		if err := execInsert(e, q, b, "posts", "id", &m.Id); err != nil {
+			errs.Add("base", err)
+			return false, errs
+		}
+	} else {
+		upd := ar.NewUpdate()
+		q, b := upd.Table("posts").Params(map[string]interface{}{
+			"id":      m.Id,
+			"user_id": m.UserId,
+			"name":    m.Name,
+		}).Where("id", m.Id).Build()
+
+		e := execer(db)
+		if tx != nil {
+			e = tx
+		}
+		if _, err := e.Exec(ar.Rebind(q), b...); err != nil {
+			errs.Add("base", err)
+			return false, errs
+		}
+	}
+
+	if isCreate {
+		if err := dispatchTx("after_create", m, tx); err != nil {
+			errs.Add("base", err)
+			return false, errs
+		}
+	} else if err := dispatchTx("after_update", m, tx); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+	if err := dispatchTx("after_save", m, tx); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
+	return true, nil
+}
+
+type PostRelation struct {
+	src      *Post
+	preloads []string
+	*ar.Relation
+}
+
+func (m *Post) newRelation() *PostRelation {
+	r := ar.NewRelation()
+	r.Table("posts").Columns(
+		"id",
+		"user_id",
+		"name",
+	)
+
+	return &PostRelation{m, nil, r}
+}
+
+func (m *Post) User() (*User, error) {
+	if m.user == nil {
+		u, err := User{}.Find(m.UserId)
+		if err != nil {
+			return nil, err
+		}
+		m.user = u
+	}
+	return m.user, nil
+}
+
+func (r *PostRelation) Preload(assoc ...string) *PostRelation {
+	r.preloads = append(r.preloads, assoc...)
+	return r
+}
+
+func (r *PostRelation) preload(results []*Post) error {
+	return preloadPostAssociations(results, r.preloads...)
+}
+
+func preloadPostAssociations(posts []*Post, assocs ...string) error {
+	for _, assoc := range assocs {
+		name, rest := splitPreloadPath(assoc)
+		switch name {
+		case "User":
+			if err := preloadPostUser(posts, rest); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("ar: unknown association %q for Preload", name)
+		}
+	}
+	return nil
+}
+
+func preloadPostUser(posts []*Post, rest string) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	byId := map[int][]*Post{}
+	idSet := map[int]bool{}
+	ids := []interface{}{}
+	for _, p := range posts {
+		byId[p.UserId] = append(byId[p.UserId], p)
+		if !idSet[p.UserId] {
+			idSet[p.UserId] = true
+			ids = append(ids, p.UserId)
+		}
+	}
+
+	d := ar.CurrentDialect()
+	q := "SELECT " + d.Quote("id") + ", " + d.Quote("name") +
+		" FROM " + d.Quote("users") + " WHERE " + d.Quote("id") + " IN (" + placeholders(len(ids)) + ")"
+	rows, err := db.Query(ar.Rebind(q), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		u := &User{}
+		if err := rows.Scan(u.fieldsByName([]string{"id", "name"})...); err != nil {
+			return err
+		}
+		users = append(users, u)
+	}
+
+	for _, u := range users {
+		for _, p := range byId[u.Id] {
+			p.user = u
+		}
+	}
+
+	if rest != "" {
+		return preloadUserAssociations(users, rest)
+	}
+	return nil
+}
+
+func (r *PostRelation) Query() ([]*Post, error) {
+	q, b := r.Build()
+	rows, err := db.Query(ar.Rebind(q), b...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*Post{}
+	for rows.Next() {
+		row := &Post{}
+		err := rows.Scan(row.fieldsByName(r.Relation.GetColumns())...)
+		if err != nil {
+			return nil, err
+		}
+		if err := dispatch("after_find", row); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	if len(r.preloads) > 0 {
+		if err := r.preload(results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (r *PostRelation) QueryTx(tx *ar.Tx) ([]*Post, error) {
+	q, b := r.Build()
+	rows, err := tx.Query(ar.Rebind(q), b...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*Post{}
+	for rows.Next() {
+		row := &Post{}
+		err := rows.Scan(row.fieldsByName(r.Relation.GetColumns())...)
+		if err != nil {
+			return nil, err
+		}
+		if err := dispatch("after_find", row); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	if len(r.preloads) > 0 {
+		if err := r.preload(results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (r *PostRelation) QueryRow() (*Post, error) {
+	q, b := r.Build()
+	row := &Post{}
+	err := db.QueryRow(ar.Rebind(q), b...).Scan(row.fieldsByName(r.Relation.GetColumns())...)
+	if err != nil {
+		return nil, err
+	}
+	if err := dispatch("after_find", row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func (m Post) First() (*Post, error) {
+	return m.newRelation().First()
+}
+
+func (r *PostRelation) First() (*Post, error) {
+	return r.Order("id", "ASC").Limit(1).QueryRow()
+}
+
+func (m Post) Last() (*Post, error) {
+	return m.newRelation().Last()
+}
+
+func (r *PostRelation) Last() (*Post, error) {
+	return r.Order("id", "DESC").Limit(1).QueryRow()
+}
+
+func (m Post) Where(cond string, args ...interface{}) *PostRelation {
+	return m.newRelation().Where(cond, args...)
+}
+
+func (r *PostRelation) Where(cond string, args ...interface{}) *PostRelation {
+	cond, args = ar.ParseCondition(cond, args...)
+	r.Relation.Where(cond, args...)
+	return r
+}
+
+func (r *PostRelation) And(cond string, args ...interface{}) *PostRelation {
+	cond, args = ar.ParseCondition(cond, args...)
+	r.Relation.And(cond, args...)
+	return r
+}
+
+func (r *PostRelation) WhereUserIdIn(ids []int) *PostRelation {
+	return r.Where("user_id__in", ids)
+}
+
+func (r *PostRelation) WhereNameLike(pattern string) *PostRelation {
+	return r.Where("name__like", pattern)
+}
+
+func (r *PostRelation) Order(column, order string) *PostRelation {
+	r.Relation.OrderBy(column, order)
+	return r
+}
+
+func (r *PostRelation) Limit(limit int) *PostRelation {
+	r.Relation.Limit(limit)
+	return r
+}
+
+func (r *PostRelation) Offset(offset int) *PostRelation {
+	r.Relation.Offset(offset)
+	return r
+}
+
+func (r *PostRelation) Group(group string, groups ...string) *PostRelation {
+	r.Relation.GroupBy(group, groups...)
+	return r
+}
+
+func (r *PostRelation) Having(cond string, args ...interface{}) *PostRelation {
+	r.Relation.Having(cond, args...)
+	return r
+}
+
+func (r *PostRelation) Explain() *PostRelation {
+	r.Relation.Explain()
+	return r
+}
+
+func (m Post) AutoMigrate() error {
+	return ar.AutoMigrate(db, m)
+}
+
+func (m Post) DryRunAutoMigrate() ([]string, error) {
+	return ar.DryRunAutoMigrate(db, m)
+}
+
+func (m Post) DeleteAll() (bool, *ar.Errors) {
+	errs := &ar.Errors{}
+	if err := dispatch("before_destroy", &m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
+	del := ar.NewDelete()
+	del.Table("posts")
+	q, b := del.Build()
+	if _, err := db.Exec(ar.Rebind(q), b...); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
+	if err := dispatch("after_destroy", &m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+	return true, nil
+}
+
+func (m Post) IsValid() (bool, *ar.Errors) {
+	result := true
+	errors := &ar.Errors{}
+	rules := map[string]*ar.Validation{}
+	for name, rule := range rules {
+		if ok, errs := ar.NewValidator(rule).IsValid(m.fieldByName(name)); !ok {
+			result = false
+			errors.Set(name, errs)
+		}
+	}
+	customs := []ar.CustomValidator{}
+	for _, c := range customs {
+		if ok, column, err := c(); !ok {
+			result = false
+			errors.Add(column, err)
+		}
+	}
+	return result, errors
+}
@@ -0,0 +1,21 @@
+package tests
+
+import "strings"
+
+// splitPreloadPath splits a path such as "Posts.Comments" into its
+// leading association name and the remaining nested path.
+func splitPreloadPath(path string) (name, rest string) {
+	if i := strings.Index(path, "."); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+// placeholders returns n comma-separated "?" for a hand-built IN (...).
+func placeholders(n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = "?"
+	}
+	return strings.Join(ph, ",")
+}
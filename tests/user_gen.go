@@ -2,14 +2,31 @@ package tests
 
 import (
 	"database/sql"
+	"fmt"
 
 	"github.com/monochromegane/argen"
 )
 
+type User struct {
+	Id   int    `ar:"pk"`
+	Name string `ar:"size:255"`
+
+	posts []*Post
+}
+
 var db *sql.DB
 
-func Use(DB *sql.DB) {
+func Use(DB *sql.DB, dialect ...string) {
 	db = DB
+	name := "mysql"
+	if len(dialect) > 0 {
+		name = dialect[0]
+	}
+	ar.UseDialect(name)
+}
+
+func Transaction(fn func(tx *ar.Tx) error) error {
+	return ar.Transaction(db, fn)
 }
 
 func (m *User) fieldByName(name string) interface{} {
@@ -66,21 +83,59 @@ func (m *User) IsPersistent() bool {
 }
 
 func (m *User) Save() (bool, *ar.Errors) {
-	if ok, errs := m.IsValid(); !ok {
+	return m.save(nil)
+}
+
+func (m *User) SaveTx(tx *ar.Tx) (bool, *ar.Errors) {
+	return m.save(tx)
+}
+
+func (m *User) save(tx *ar.Tx) (bool, *ar.Errors) {
+	errs := &ar.Errors{}
+
+	if err := dispatch("before_validation", m); err != nil {
+		errs.Add("base", err)
 		return false, errs
 	}
-	errs := &ar.Errors{}
-	if m.IsNewRecord() {
+	ok, validationErrs := m.IsValid()
+	if err := dispatch("after_validation", m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+	if !ok {
+		return false, validationErrs
+	}
+
+	if err := dispatch("before_save", m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
+	isCreate := m.IsNewRecord()
+	if isCreate {
+		if err := dispatch("before_create", m); err != nil {
+			errs.Add("base", err)
+			return false, errs
+		}
+	} else if err := dispatch("before_update", m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
+	if isCreate {
 		ins := ar.NewInsert()
 		q, b := ins.Table("users").Params(map[string]interface{}{
 			"name": m.Name,
 		}).Build()
 
-		if _, err := db.Exec(q, b...); err != nil {
+		e := execer(db)
+		if tx != nil {
+			e = tx
+		}
+		if err := execInsert(e, q, b, "users", "id", &m.Id); err != nil {
 			errs.Add("base", err)
 			return false, errs
 		}
-		return true, nil
 	} else {
 		upd := ar.NewUpdate()
 		q, b := upd.Table("users").Params(map[string]interface{}{
@@ -88,19 +143,116 @@ func (m *User) Save() (bool, *ar.Errors) {
 			"name": m.Name,
 		}).Where("id", m.Id).Build()
 
-		if _, err := db.Exec(q, b...); err != nil {
+		e := execer(db)
+		if tx != nil {
+			e = tx
+		}
+		if _, err := e.Exec(ar.Rebind(q), b...); err != nil {
+			errs.Add("base", err)
+			return false, errs
+		}
+	}
+
+	if isCreate {
+		if err := dispatchTx("after_create", m, tx); err != nil {
 			errs.Add("base", err)
 			return false, errs
 		}
-		return true, nil
+	} else if err := dispatchTx("after_update", m, tx); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+	if err := dispatchTx("after_save", m, tx); err != nil {
+		errs.Add("base", err)
+		return false, errs
 	}
+
+	return true, nil
 }
 
 type UserRelation struct {
-	src *User
+	src      *User
+	preloads []string
 	*ar.Relation
 }
 
+func (m *User) Posts() ([]*Post, error) {
+	if m.posts == nil {
+		posts, err := Post{}.Where("user_id", m.Id).Query()
+		if err != nil {
+			return nil, err
+		}
+		m.posts = posts
+	}
+	return m.posts, nil
+}
+
+func (r *UserRelation) Preload(assoc ...string) *UserRelation {
+	r.preloads = append(r.preloads, assoc...)
+	return r
+}
+
+func (r *UserRelation) preload(results []*User) error {
+	return preloadUserAssociations(results, r.preloads...)
+}
+
+func preloadUserAssociations(users []*User, assocs ...string) error {
+	for _, assoc := range assocs {
+		name, rest := splitPreloadPath(assoc)
+		switch name {
+		case "Posts":
+			if err := preloadUserPosts(users, rest); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("ar: unknown association %q for Preload", name)
+		}
+	}
+	return nil
+}
+
+func preloadUserPosts(users []*User, rest string) error {
+	if len(users) == 0 {
+		return nil
+	}
+	byId := map[int]*User{}
+	ids := make([]interface{}, len(users))
+	for i, u := range users {
+		ids[i] = u.Id
+		byId[u.Id] = u
+		u.posts = []*Post{}
+	}
+
+	d := ar.CurrentDialect()
+	q := "SELECT " + d.Quote("id") + ", " + d.Quote("user_id") + ", " + d.Quote("name") +
+		" FROM " + d.Quote("posts") + " WHERE " + d.Quote("user_id") + " IN (" + placeholders(len(ids)) + ")"
+	rows, err := db.Query(ar.Rebind(q), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	posts := []*Post{}
+	for rows.Next() {
+		p := &Post{}
+		if err := rows.Scan(p.fieldsByName([]string{"id", "user_id", "name"})...); err != nil {
+			return err
+		}
+		posts = append(posts, p)
+	}
+
+	for _, p := range posts {
+		if u, ok := byId[p.UserId]; ok {
+			u.posts = append(u.posts, p)
+		}
+	}
+
+	if rest != "" {
+		return preloadPostAssociations(posts, rest)
+	}
+	return nil
+}
+
 func (m *User) newRelation() *UserRelation {
 	r := ar.NewRelation()
 	r.Table("users").Columns(
@@ -108,12 +260,12 @@ func (m *User) newRelation() *UserRelation {
 		"name",
 	)
 
-	return &UserRelation{m, r}
+	return &UserRelation{m, nil, r}
 }
 
 func (r *UserRelation) Query() ([]*User, error) {
 	q, b := r.Build()
-	rows, err := db.Query(q, b...)
+	rows, err := db.Query(ar.Rebind(q), b...)
 	if err != nil {
 		return nil, err
 	}
@@ -126,18 +278,57 @@ func (r *UserRelation) Query() ([]*User, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := dispatch("after_find", row); err != nil {
+			return nil, err
+		}
 		results = append(results, row)
 	}
+	if len(r.preloads) > 0 {
+		if err := r.preload(results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (r *UserRelation) QueryTx(tx *ar.Tx) ([]*User, error) {
+	q, b := r.Build()
+	rows, err := tx.Query(ar.Rebind(q), b...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*User{}
+	for rows.Next() {
+		row := &User{}
+		err := rows.Scan(row.fieldsByName(r.Relation.GetColumns())...)
+		if err != nil {
+			return nil, err
+		}
+		if err := dispatch("after_find", row); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	if len(r.preloads) > 0 {
+		if err := r.preload(results); err != nil {
+			return nil, err
+		}
+	}
 	return results, nil
 }
 
 func (r *UserRelation) QueryRow() (*User, error) {
 	q, b := r.Build()
 	row := &User{}
-	err := db.QueryRow(q, b...).Scan(row.fieldsByName(r.Relation.GetColumns())...)
+	err := db.QueryRow(ar.Rebind(q), b...).Scan(row.fieldsByName(r.Relation.GetColumns())...)
 	if err != nil {
 		return nil, err
 	}
+	if err := dispatch("after_find", row); err != nil {
+		return nil, err
+	}
 	return row, nil
 }
 
@@ -162,15 +353,41 @@ func (m User) Where(cond string, args ...interface{}) *UserRelation {
 }
 
 func (r *UserRelation) Where(cond string, args ...interface{}) *UserRelation {
+	cond, args = ar.ParseCondition(cond, args...)
 	r.Relation.Where(cond, args...)
 	return r
 }
 
 func (r *UserRelation) And(cond string, args ...interface{}) *UserRelation {
+	cond, args = ar.ParseCondition(cond, args...)
 	r.Relation.And(cond, args...)
 	return r
 }
 
+func (r *UserRelation) WhereIdGt(id int) *UserRelation {
+	return r.Where("id__gt", id)
+}
+
+func (r *UserRelation) WhereIdGte(id int) *UserRelation {
+	return r.Where("id__gte", id)
+}
+
+func (m User) WhereIdIn(ids []int) *UserRelation {
+	return m.newRelation().WhereIdIn(ids)
+}
+
+func (r *UserRelation) WhereIdIn(ids []int) *UserRelation {
+	return r.Where("id__in", ids)
+}
+
+func (m User) WhereNameLike(pattern string) *UserRelation {
+	return m.newRelation().WhereNameLike(pattern)
+}
+
+func (r *UserRelation) WhereNameLike(pattern string) *UserRelation {
+	return r.Where("name__like", pattern)
+}
+
 func (r *UserRelation) Order(column, order string) *UserRelation {
 	r.Relation.OrderBy(column, order)
 	return r
@@ -201,12 +418,30 @@ func (r *UserRelation) Explain() *UserRelation {
 	return r
 }
 
+func (m User) AutoMigrate() error {
+	return ar.AutoMigrate(db, m)
+}
+
+func (m User) DryRunAutoMigrate() ([]string, error) {
+	return ar.DryRunAutoMigrate(db, m)
+}
+
 func (m User) DeleteAll() (bool, *ar.Errors) {
 	errs := &ar.Errors{}
+	if err := dispatch("before_destroy", &m); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
 	del := ar.NewDelete()
 	del.Table("users")
 	q, b := del.Build()
-	if _, err := db.Exec(q, b...); err != nil {
+	if _, err := db.Exec(ar.Rebind(q), b...); err != nil {
+		errs.Add("base", err)
+		return false, errs
+	}
+
+	if err := dispatch("after_destroy", &m); err != nil {
 		errs.Add("base", err)
 		return false, errs
 	}
@@ -231,4 +466,4 @@ func (m User) IsValid() (bool, *ar.Errors) {
 		}
 	}
 	return result, errors
-}
\ No newline at end of file
+}
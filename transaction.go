@@ -0,0 +1,70 @@
+package ar
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Tx wraps an in-flight transaction, passed to SaveTx/QueryTx in place
+// of the package-level *sql.DB.
+type Tx struct {
+	*sql.Tx
+	depth int
+}
+
+// Transaction commits on a nil return from fn, rolls back on error or
+// panic, and re-raises the panic after rolling back.
+func Transaction(db *sql.DB, fn func(tx *Tx) error) error {
+	sqlTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	return runInTransaction(&Tx{Tx: sqlTx}, fn)
+}
+
+// Transaction nests a savepoint inside tx, so an inner failure unwinds
+// only this call's work rather than the whole outer transaction.
+func (tx *Tx) Transaction(fn func(tx *Tx) error) error {
+	tx.depth++
+	savepoint := fmt.Sprintf("sp_%d", tx.depth)
+	nested := &Tx{Tx: tx.Tx, depth: tx.depth}
+
+	if _, err := tx.Exec("SAVEPOINT " + savepoint); err != nil {
+		return err
+	}
+
+	err := runWithSavepoint(tx, savepoint, fn, nested)
+	return err
+}
+
+func runWithSavepoint(tx *Tx, savepoint string, fn func(tx *Tx) error, nested *Tx) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Exec("ROLLBACK TO " + savepoint)
+			panic(p)
+		}
+		if err != nil {
+			tx.Exec("ROLLBACK TO " + savepoint)
+			return
+		}
+		_, err = tx.Exec("RELEASE " + savepoint)
+	}()
+	err = fn(nested)
+	return err
+}
+
+func runInTransaction(tx *Tx, fn func(tx *Tx) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	err = fn(tx)
+	return err
+}